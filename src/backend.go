@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backend is implemented by every scanner driver the tool supports, whether
+// over eSCL (network/AirScan) or SANE (local/network device). It replaces
+// the ad-hoc URL-sniffing that used to live in main.
+type Backend interface {
+	// Scan performs a single-page scan and returns the path to the scanned file.
+	Scan(format string) (string, error)
+	// ScanBatch scans every page available in the document feeder, assembles
+	// them into a single multi-page document, and returns the resulting file
+	// paths. The assembled document is always the first entry; any remaining
+	// entries are the raw per-page scans, kept around for debugging/cleanup.
+	ScanBatch(opts BatchOptions) ([]string, error)
+	// Capabilities reports what the device can do, used to validate scan
+	// options and to populate `--discover` output.
+	Capabilities() (BackendCapabilities, error)
+	// Status reports the current device state (e.g. "Idle", "Processing",
+	// "unavailable").
+	Status() (string, error)
+}
+
+// BackendCapabilities is a backend-agnostic summary of what a device
+// supports. eSCL devices populate it from ScannerCapabilities; SANE devices
+// populate it with the fixed set scanimage supports.
+type BackendCapabilities struct {
+	Formats []string
+}
+
+// backendFactory builds a Backend for a device at the given URL/device name,
+// using retryConfig for any HTTP client it creates. interactive controls
+// whether the backend may fall back to prompting on stdin for errors an
+// HTTP retry can't fix (see ESCLScanner.promptToDismissIfScannerBusy);
+// callers with no attached terminal should pass false.
+type backendFactory func(url string, retryConfig RetryConfig, interactive bool) Backend
+
+// backendRegistry maps a backend kind ("escl", "sane") to its factory.
+var backendRegistry = map[string]backendFactory{
+	"escl": func(url string, retryConfig RetryConfig, interactive bool) Backend {
+		return NewESCLScannerWithRetryConfig(url, retryConfig, interactive)
+	},
+	"sane": func(url string, retryConfig RetryConfig, interactive bool) Backend { return NewSANEScanner(url) },
+}
+
+// DetectBackendKind infers which backend a scanner URL belongs to: eSCL
+// scanners are addressed by HTTP(S) URL, SANE devices by a bare device name.
+func DetectBackendKind(url string) string {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return "escl"
+	}
+	return "sane"
+}
+
+// NewBackend resolves url to a backend kind and constructs the matching
+// Backend implementation via the registry, using retryConfig for its HTTP
+// client where applicable. interactive is forwarded to the backend; pass
+// false for callers with no attached terminal, such as the serve daemon.
+func NewBackend(url string, retryConfig RetryConfig, interactive bool) (Backend, error) {
+	kind := DetectBackendKind(url)
+	factory, ok := backendRegistry[kind]
+	if !ok {
+		return nil, fmt.Errorf("no backend registered for kind %q", kind)
+	}
+	return factory(url, retryConfig, interactive), nil
+}
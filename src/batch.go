@@ -0,0 +1,11 @@
+package main
+
+// BatchOptions configures a multi-page scan from the document feeder.
+type BatchOptions struct {
+	// Format is the output file extension for the assembled document (e.g. ".pdf").
+	Format string
+	// MaxPages caps the number of pages pulled from the feeder. Zero means no limit.
+	MaxPages int
+	// PreProcess enables binarization/deskew of each page before PDF assembly.
+	PreProcess bool
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/robfig/cron/v3"
+)
+
+// watchedExtensions are the dropped-file types the watched folder picks up
+// for upload.
+var watchedExtensions = map[string]bool{
+	".pdf": true, ".jpg": true, ".jpeg": true, ".png": true,
+}
+
+// daemonState tracks the long-lived daemon's health for /healthz and
+// /metrics: the last successful job, the last error, and how many scan/
+// upload jobs are currently running.
+type daemonState struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastError   string
+	errorCount  int
+	inFlight    int
+}
+
+func (s *daemonState) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight++
+}
+
+func (s *daemonState) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	if err != nil {
+		s.errorCount++
+		s.lastError = err.Error()
+		return
+	}
+	s.lastSuccess = time.Now()
+}
+
+func (s *daemonState) snapshot() (lastSuccess time.Time, lastError string, errorCount, inFlight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSuccess, s.lastError, s.errorCount, s.inFlight
+}
+
+// daemon holds everything a running `serve` instance needs to turn a
+// trigger (HTTP request, watched file, cron tick) into a scan-and-upload or
+// upload-only job.
+type daemon struct {
+	backend    Backend
+	paperless  *PaperlessClient
+	state      *daemonState
+	serveToken string
+	uploadTag  string
+
+	// scanMu serializes the two triggers that drive the physical
+	// scanner (the cron schedule and POST /scan) so they can't issue
+	// concurrent ScanBatch calls against the same device. Watched-folder
+	// uploads don't scan, so they aren't gated by it.
+	scanMu sync.Mutex
+}
+
+// runScanAndUpload performs a full ADF batch scan and uploads the result,
+// tagging it with d.uploadTag when set. Used by both the cron schedule and
+// the POST /scan button endpoint.
+func (d *daemon) runScanAndUpload() error {
+	d.state.begin()
+	var err error
+	defer func() { d.state.finish(err) }()
+
+	var pages []string
+	pages, err = d.backend.ScanBatch(BatchOptions{Format: ".pdf", PreProcess: true})
+	if err != nil {
+		err = fmt.Errorf("scan failed: %w", err)
+		return err
+	}
+	defer func() {
+		for i := 1; i < len(pages); i++ {
+			os.Remove(pages[i])
+		}
+	}()
+
+	opts := UploadOptions{}
+	if d.uploadTag != "" {
+		opts.Tags = []string{d.uploadTag}
+	}
+
+	_, err = d.paperless.UploadDocumentWithOptions(pages[0], opts)
+	os.Remove(pages[0])
+	if err != nil {
+		err = fmt.Errorf("upload failed: %w", err)
+		return err
+	}
+	return nil
+}
+
+// uploadDroppedFile uploads a file dropped into the watched folder as-is
+// (no scan step), tagging it with d.uploadTag when set.
+func (d *daemon) uploadDroppedFile(path string) error {
+	d.state.begin()
+	var err error
+	defer func() { d.state.finish(err) }()
+
+	opts := UploadOptions{}
+	if d.uploadTag != "" {
+		opts.Tags = []string{d.uploadTag}
+	}
+
+	_, err = d.paperless.UploadDocumentWithOptions(path, opts)
+	return err
+}
+
+// checkAuth validates the request's Bearer token against d.serveToken.
+func (d *daemon) checkAuth(r *http.Request) bool {
+	if d.serveToken == "" {
+		return true
+	}
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return got == d.serveToken
+}
+
+func (d *daemon) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !d.checkAuth(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !d.scanMu.TryLock() {
+		http.Error(w, "a scan is already in progress", http.StatusConflict)
+		return
+	}
+
+	go func() {
+		defer d.scanMu.Unlock()
+		if err := d.runScanAndUpload(); err != nil {
+			log.Printf("Triggered scan failed: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "scan triggered")
+}
+
+func (d *daemon) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	lastSuccess, lastError, errorCount, inFlight := d.state.snapshot()
+
+	status := "ok"
+	if inFlight == 0 && lastSuccess.IsZero() && errorCount > 0 {
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       status,
+		"last_success": lastSuccess,
+		"last_error":   lastError,
+		"error_count":  errorCount,
+		"in_flight":    inFlight,
+	})
+}
+
+func (d *daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	lastSuccess, _, errorCount, inFlight := d.state.snapshot()
+
+	lastSuccessSeconds := float64(0)
+	if !lastSuccess.IsZero() {
+		lastSuccessSeconds = float64(lastSuccess.Unix())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP paperless_scanner_last_success_timestamp_seconds Unix time of the last successful scan/upload job.\n")
+	fmt.Fprintf(w, "# TYPE paperless_scanner_last_success_timestamp_seconds gauge\n")
+	fmt.Fprintf(w, "paperless_scanner_last_success_timestamp_seconds %g\n", lastSuccessSeconds)
+	fmt.Fprintf(w, "# HELP paperless_scanner_job_errors_total Total scan/upload jobs that ended in an error.\n")
+	fmt.Fprintf(w, "# TYPE paperless_scanner_job_errors_total counter\n")
+	fmt.Fprintf(w, "paperless_scanner_job_errors_total %d\n", errorCount)
+	fmt.Fprintf(w, "# HELP paperless_scanner_jobs_in_flight Scan/upload jobs currently running.\n")
+	fmt.Fprintf(w, "# TYPE paperless_scanner_jobs_in_flight gauge\n")
+	fmt.Fprintf(w, "paperless_scanner_jobs_in_flight %d\n", inFlight)
+}
+
+// watchFolder watches dir for newly created files with a recognized
+// extension and uploads each one once it looks like it has finished being
+// written (its size is unchanged across two checks a second apart). It
+// blocks until ctx is canceled.
+func (d *daemon) watchFolder(ctx context.Context, dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create folder watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	processedDir := filepath.Join(dir, ".processed")
+	if err := os.MkdirAll(processedDir, 0755); err != nil {
+		return fmt.Errorf("failed to create processed-files directory: %w", err)
+	}
+
+	log.Printf("Watching %s for dropped scans", dir)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			if !watchedExtensions[strings.ToLower(filepath.Ext(event.Name))] {
+				continue
+			}
+			go d.handleDroppedFile(event.Name, processedDir)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Warning: folder watch error: %v", err)
+		}
+	}
+}
+
+// handleDroppedFile waits for a dropped file to stop growing, uploads it,
+// and moves it into processedDir on success so a restart doesn't re-upload
+// it.
+func (d *daemon) handleDroppedFile(path, processedDir string) {
+	if !waitUntilStable(path) {
+		log.Printf("Warning: %s disappeared before it could be uploaded", path)
+		return
+	}
+
+	log.Printf("Uploading dropped file: %s", path)
+	if err := d.uploadDroppedFile(path); err != nil {
+		log.Printf("Failed to upload %s: %v", path, err)
+		return
+	}
+
+	dest := filepath.Join(processedDir, filepath.Base(path))
+	if err := os.Rename(path, dest); err != nil {
+		log.Printf("Warning: failed to move %s to %s: %v", path, dest, err)
+	}
+}
+
+// waitUntilStable polls path's size once a second until two consecutive
+// checks agree, to let a slow copy/write finish before uploading it. After
+// 10 checks it returns true even if the size is still changing. It returns
+// false if the file disappears (e.g. the writer itself renamed it away)
+// before settling.
+func waitUntilStable(path string) bool {
+	var lastSize int64 = -1
+	for i := 0; i < 10; i++ {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		if info.Size() == lastSize {
+			return true
+		}
+		lastSize = info.Size()
+		time.Sleep(1 * time.Second)
+	}
+	return true
+}
+
+// runServeCommand implements the `serve` subcommand: a long-lived daemon
+// that scans/uploads on three triggers -- a watched folder, a cron
+// schedule, and an authenticated POST /scan button endpoint -- and exposes
+// /healthz and /metrics for monitoring.
+func runServeCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	scannerURL := fs.String("scanner_url", "", "Scanner URL (overrides SCANNER_URL env var)")
+	paperlessURL := fs.String("paperless_url", "", "Paperless URL (overrides PAPERLESS_URL env var)")
+	paperlessToken := fs.String("paperless_token", "", "Paperless API token (overrides PAPERLESS_TOKEN env var)")
+	watchDir := fs.String("watch-dir", "", "Directory to watch for dropped PDF/JPG/PNG files to upload")
+	watchTag := fs.String("watch-tag", "", "Tag applied to documents uploaded from --watch-dir, --schedule, or POST /scan")
+	schedule := fs.String("schedule", "", "Cron schedule (standard 5-field syntax) for an automatic ADF batch scan and upload")
+	listenAddr := fs.String("listen-addr", ":8080", "Address the /scan, /healthz, and /metrics HTTP endpoints listen on")
+	serveToken := fs.String("serve-token", "", "Bearer token required by POST /scan (defaults to PAPERLESS_TOKEN)")
+	maxRetries := fs.Int("max-retries", DefaultRetryConfig.MaxRetries, "Maximum attempts for a scanner/Paperless request before giving up")
+	retryBackoff := fs.Duration("retry-backoff", DefaultRetryConfig.BaseBackoff, "Base backoff between retries (doubles each attempt, capped and jittered)")
+	requestTimeout := fs.Duration("request-timeout", DefaultRetryConfig.RequestTimeout, "Per-request timeout for scanner/Paperless HTTP calls")
+	fs.Parse(args)
+
+	config, err := loadConfig(true, *scannerURL, *paperlessURL, *paperlessToken)
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	retryConfig := RetryConfig{MaxRetries: *maxRetries, BaseBackoff: *retryBackoff, RequestTimeout: *requestTimeout}
+
+	backend, err := NewBackend(config.ScannerURL, retryConfig, false)
+	if err != nil {
+		log.Fatalf("Failed to initialize scanner backend: %v", err)
+	}
+
+	token := *serveToken
+	if token == "" {
+		token = config.PaperlessToken
+	}
+
+	d := &daemon{
+		backend:    backend,
+		paperless:  NewPaperlessClient(config.PaperlessURL, config.PaperlessToken, retryConfig),
+		state:      &daemonState{},
+		serveToken: token,
+		uploadTag:  *watchTag,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	if *watchDir != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.watchFolder(ctx, *watchDir); err != nil {
+				log.Printf("Folder watcher stopped: %v", err)
+			}
+		}()
+	}
+
+	if *schedule != "" {
+		c := cron.New()
+		if _, err := c.AddFunc(*schedule, func() {
+			if !d.scanMu.TryLock() {
+				log.Printf("Scheduled scan skipped: a scan is already in progress")
+				return
+			}
+			defer d.scanMu.Unlock()
+			if err := d.runScanAndUpload(); err != nil {
+				log.Printf("Scheduled scan failed: %v", err)
+			}
+		}); err != nil {
+			log.Fatalf("Invalid --schedule %q: %v", *schedule, err)
+		}
+		c.Start()
+		log.Printf("Scheduled ADF scan enabled: %s", *schedule)
+		go func() {
+			<-ctx.Done()
+			<-c.Stop().Done()
+		}()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", d.handleScan)
+	mux.HandleFunc("/healthz", d.handleHealthz)
+	mux.HandleFunc("/metrics", d.handleMetrics)
+
+	server := &http.Server{Addr: *listenAddr, Handler: mux}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Printf("Serving /scan, /healthz, and /metrics on %s", *listenAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server stopped: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+
+	wg.Wait()
+}
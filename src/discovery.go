@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// Service types browsed for scanner discovery: AirScan (eSCL, plain and TLS)
+// and the SANE network daemon.
+var discoveryServiceTypes = []string{"_uscan._tcp", "_uscans._tcp", "_scanner._tcp"}
+
+// discoveryTimeout bounds how long we wait for mDNS/DNS-SD responses.
+const discoveryTimeout = 5 * time.Second
+
+// cacheTTL controls how long a cached device list is considered fresh.
+const cacheTTL = 24 * time.Hour
+
+// Device describes a scanner found on the LAN.
+type Device struct {
+	Name    string   `json:"name"`
+	Model   string   `json:"model"`
+	URL     string   `json:"url"`
+	Backend string   `json:"backend"`
+	Formats []string `json:"formats,omitempty"`
+}
+
+// deviceCache is the on-disk cache format written to devices.json.
+type deviceCache struct {
+	CachedAt time.Time `json:"cached_at"`
+	Devices  []Device  `json:"devices"`
+}
+
+// Discover browses the LAN for eSCL (AirScan) and SANE network scanners via
+// mDNS/DNS-SD and returns every device found, browsing each service type
+// for discoveryTimeout in turn.
+func Discover() ([]Device, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mDNS resolver: %w", err)
+	}
+
+	var devices []Device
+
+	// Each service type gets its own discoveryTimeout budget rather than
+	// sharing one deadline across all three: zeroconf's Browse blocks until
+	// its context expires, so a shared deadline would let the first service
+	// type (_uscan._tcp) consume the whole budget and leave none for the
+	// rest.
+	for _, serviceType := range discoveryServiceTypes {
+		ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+
+		entries := make(chan *zeroconf.ServiceEntry)
+		found := make(chan struct{})
+
+		go func() {
+			for entry := range entries {
+				devices = append(devices, deviceFromServiceEntry(serviceType, entry))
+			}
+			close(found)
+		}()
+
+		if err := resolver.Browse(ctx, serviceType, "local.", entries); err != nil {
+			log.Printf("Warning: mDNS browse for %s failed: %v", serviceType, err)
+			close(entries)
+			cancel()
+			continue
+		}
+
+		<-ctx.Done()
+		<-found
+		cancel()
+	}
+
+	for i := range devices {
+		backend, err := NewBackend(devices[i].URL, DefaultRetryConfig, true)
+		if err != nil {
+			continue
+		}
+		caps, err := backend.Capabilities()
+		if err != nil {
+			log.Printf("Warning: failed to query capabilities for %s: %v", devices[i].Name, err)
+			continue
+		}
+		devices[i].Formats = caps.Formats
+	}
+
+	return devices, nil
+}
+
+// deviceFromServiceEntry converts a raw mDNS/DNS-SD service entry into a
+// Device, deriving a scanner URL from the entry's address and port and the
+// model from its `ty` TXT record. The `rs` (resource path) TXT record is not
+// used.
+func deviceFromServiceEntry(serviceType string, entry *zeroconf.ServiceEntry) Device {
+	backend := "escl"
+	scheme := "http"
+	if serviceType == "_uscans._tcp" {
+		scheme = "https"
+	}
+	if serviceType == "_scanner._tcp" {
+		backend = "sane"
+	}
+
+	host := entry.HostName
+	if len(entry.AddrIPv4) > 0 {
+		host = entry.AddrIPv4[0].String()
+	}
+
+	model := ""
+	for _, txt := range entry.Text {
+		if v, ok := strings.CutPrefix(txt, "ty="); ok {
+			model = v
+		}
+	}
+
+	url := fmt.Sprintf("%s://%s:%d", scheme, host, entry.Port)
+	if backend == "sane" {
+		url = fmt.Sprintf("net:%s", host)
+	}
+
+	return Device{
+		Name:    entry.Instance,
+		Model:   model,
+		URL:     url,
+		Backend: backend,
+	}
+}
+
+// cachePath returns the location of the discovery cache file, honoring
+// $XDG_CACHE_HOME when set.
+func cachePath() (string, error) {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "paperless-scanner", "devices.json"), nil
+}
+
+// loadCachedDevices reads the discovery cache, returning (nil, nil) if it's
+// missing or has expired.
+func loadCachedDevices() ([]Device, error) {
+	path, err := cachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read discovery cache: %w", err)
+	}
+
+	var cache deviceCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery cache: %w", err)
+	}
+
+	if time.Since(cache.CachedAt) > cacheTTL {
+		return nil, nil
+	}
+
+	return cache.Devices, nil
+}
+
+// saveCachedDevices writes devices to the discovery cache with the current
+// timestamp.
+func saveCachedDevices(devices []Device) error {
+	path, err := cachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(deviceCache{CachedAt: time.Now(), Devices: devices}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery cache: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// DiscoverWithCache returns cached devices when fresh, otherwise performs a
+// live discovery and refreshes the cache.
+func DiscoverWithCache() ([]Device, error) {
+	if cached, err := loadCachedDevices(); err != nil {
+		log.Printf("Warning: failed to read discovery cache: %v", err)
+	} else if cached != nil {
+		return cached, nil
+	}
+
+	devices, err := Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := saveCachedDevices(devices); err != nil {
+		log.Printf("Warning: failed to write discovery cache: %v", err)
+	}
+
+	return devices, nil
+}
+
+// ResolveScannerName looks up name among discovered devices (refreshing the
+// cache if needed) and returns its URL.
+func ResolveScannerName(name string) (string, error) {
+	devices, err := DiscoverWithCache()
+	if err != nil {
+		return "", fmt.Errorf("failed to discover scanners: %w", err)
+	}
+
+	for _, d := range devices {
+		if strings.EqualFold(d.Name, name) {
+			return d.URL, nil
+		}
+	}
+
+	return "", fmt.Errorf("no scanner named %q found (run --discover to list available devices)", name)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ScannerCapabilities is a parsed view of the eSCL ScannerCapabilities XML,
+// narrowed down to what ScanOptions needs to validate: per-source supported
+// resolutions, color modes, max scan region, and the document formats
+// listed in the Platen setting profiles (formats listed only for the ADF
+// are not read).
+type ScannerCapabilities struct {
+	XMLName         xml.Name            `xml:"ScannerCapabilities"`
+	MakeAndModel    string              `xml:"MakeAndModel"`
+	DocumentFormats []string            `xml:"Platen>PlatenInputCaps>SettingProfiles>SettingProfile>DocumentFormats>DocumentFormat"`
+	Platen          *SourceCapabilities `xml:"Platen>PlatenInputCaps"`
+	Adf             *SourceCapabilities `xml:"Adf>AdfSimplexInputCaps"`
+	AdfDuplex       *SourceCapabilities `xml:"Adf>AdfDuplexInputCaps"`
+}
+
+// SourceCapabilities describes what a single input source (Platen, Feeder,
+// or Duplex) supports.
+type SourceCapabilities struct {
+	MaxWidth    int      `xml:"MaxWidth"`
+	MaxHeight   int      `xml:"MaxHeight"`
+	ColorModes  []string `xml:"SettingProfiles>SettingProfile>ColorModes>ColorMode"`
+	Resolutions []int    `xml:"SettingProfiles>SettingProfile>SupportedResolutions>DiscreteResolutions>DiscreteResolution>XResolution"`
+}
+
+// forSource returns the capabilities for the named input source
+// (Platen, Feeder, or Duplex), or nil if the device doesn't report one.
+func (c *ScannerCapabilities) forSource(source string) *SourceCapabilities {
+	switch source {
+	case "Feeder":
+		return c.Adf
+	case "Duplex":
+		return c.AdfDuplex
+	default:
+		return c.Platen
+	}
+}
+
+// GetCapabilities fetches and parses /eSCL/ScannerCapabilities.
+func (s *ESCLScanner) GetCapabilities() (*ScannerCapabilities, error) {
+	url := fmt.Sprintf("%s/eSCL/ScannerCapabilities", s.baseURL)
+
+	resp, err := s.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scanner capabilities: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scanner capabilities returned non-OK status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scanner capabilities response: %w", err)
+	}
+
+	var caps ScannerCapabilities
+	if err := xml.Unmarshal(body, &caps); err != nil {
+		return nil, fmt.Errorf("failed to parse scanner capabilities XML: %w", err)
+	}
+
+	return &caps, nil
+}
+
+// ScanOptions describes a user-requested scan beyond the hardcoded defaults
+// in Scan: resolution, color mode, input source, intent, and content region.
+type ScanOptions struct {
+	Resolution int    // DPI, e.g. 300
+	ColorMode  string // RGB24, Grayscale8, BlackAndWhite1
+	Source     string // Platen, Feeder, Duplex
+	Intent     string // Document, Photo, TextAndGraphic, Preview
+	Region     string // "<width>x<height>" in ThreeHundredthsOfInches, e.g. "2550x3508"
+	Format     string // output file extension, e.g. ".pdf"
+}
+
+// withDefaults fills in the tool's historical hardcoded defaults for any
+// field left unset.
+func (o ScanOptions) withDefaults() ScanOptions {
+	if o.Resolution == 0 {
+		o.Resolution = 300
+	}
+	if o.ColorMode == "" {
+		o.ColorMode = "RGB24"
+	}
+	if o.Source == "" {
+		o.Source = "Platen"
+	}
+	if o.Intent == "" {
+		o.Intent = "Document"
+	}
+	if o.Region == "" {
+		o.Region = "2550x3508"
+	}
+	if o.Format == "" {
+		o.Format = ".pdf"
+	}
+	return o
+}
+
+// validateAgainstCapabilities checks each option against what caps reports
+// for the requested source, falling back to a supported alternative and
+// logging a warning whenever a request isn't supported: the closest
+// supported resolution, the first listed color mode or document format, and
+// a region clamped to the source's maximum size.
+// caps may be nil (capabilities couldn't be queried), in which case options
+// are passed through unchanged.
+func validateAgainstCapabilities(opts ScanOptions, caps *ScannerCapabilities) ScanOptions {
+	if caps == nil {
+		return opts
+	}
+
+	source := caps.forSource(opts.Source)
+	if source == nil {
+		log.Printf("Warning: scanner does not report capabilities for source %q, using requested options as-is", opts.Source)
+		return opts
+	}
+
+	if len(source.ColorModes) > 0 && !contains(source.ColorModes, opts.ColorMode) {
+		log.Printf("Warning: color mode %q unsupported on %s, falling back to %s", opts.ColorMode, opts.Source, source.ColorModes[0])
+		opts.ColorMode = source.ColorModes[0]
+	}
+
+	if len(source.Resolutions) > 0 && !containsInt(source.Resolutions, opts.Resolution) {
+		fallback := closestInt(source.Resolutions, opts.Resolution)
+		log.Printf("Warning: resolution %d unsupported on %s, falling back to %d", opts.Resolution, opts.Source, fallback)
+		opts.Resolution = fallback
+	}
+
+	if len(caps.DocumentFormats) > 0 {
+		mimeType, err := getESCLMimeType(opts.Format)
+		if err != nil || !contains(caps.DocumentFormats, mimeType) {
+			log.Printf("Warning: format %q unsupported, falling back to %s", opts.Format, caps.DocumentFormats[0])
+			opts.Format = extensionForMimeType(caps.DocumentFormats[0])
+		}
+	}
+
+	if source.MaxWidth > 0 && source.MaxHeight > 0 {
+		if width, height, ok := parseRegion(opts.Region); ok {
+			clampedWidth, clampedHeight := width, height
+			if clampedWidth > source.MaxWidth {
+				clampedWidth = source.MaxWidth
+			}
+			if clampedHeight > source.MaxHeight {
+				clampedHeight = source.MaxHeight
+			}
+			if clampedWidth != width || clampedHeight != height {
+				log.Printf("Warning: region %s exceeds max scan area %dx%d on %s, clamping to %dx%d",
+					opts.Region, source.MaxWidth, source.MaxHeight, opts.Source, clampedWidth, clampedHeight)
+				opts.Region = formatRegion(clampedWidth, clampedHeight)
+			}
+		}
+	}
+
+	return opts
+}
+
+// parseRegion parses a "<width>x<height>" region string (in
+// ThreeHundredthsOfInches) into its two components.
+func parseRegion(region string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(region, "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, 0, false
+	}
+	height, err = strconv.Atoi(h)
+	if err != nil {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// formatRegion is the inverse of parseRegion.
+func formatRegion(width, height int) string {
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, target int) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// closestInt returns the value in values nearest to target.
+func closestInt(values []int, target int) int {
+	best := values[0]
+	for _, v := range values {
+		if abs(v-target) < abs(best-target) {
+			best = v
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// extensionForMimeType is the inverse of getESCLMimeType, used when falling
+// back to a supported DocumentFormat reported by the scanner.
+func extensionForMimeType(mimeType string) string {
+	switch mimeType {
+	case "application/pdf":
+		return ".pdf"
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	default:
+		return ".pdf"
+	}
+}
@@ -16,7 +16,9 @@ import (
 )
 
 type ESCLScanner struct {
-	baseURL string
+	baseURL     string
+	client      *http.Client
+	interactive bool
 }
 
 // ScannerStatusResponse represents the eSCL ScannerStatus XML response
@@ -27,14 +29,23 @@ type ScannerStatusResponse struct {
 }
 
 func NewESCLScanner(baseURL string) *ESCLScanner {
-	return &ESCLScanner{baseURL: baseURL}
+	return NewESCLScannerWithRetryConfig(baseURL, DefaultRetryConfig, true)
+}
+
+// NewESCLScannerWithRetryConfig builds an ESCLScanner whose HTTP client
+// retries retriable failures per retryConfig, instead of the package-wide
+// DefaultRetryConfig. interactive controls whether a persistent 503 falls
+// back to prompting on stdin (see promptToDismissIfScannerBusy); callers
+// with no attached terminal, like the serve daemon, should pass false.
+func NewESCLScannerWithRetryConfig(baseURL string, retryConfig RetryConfig, interactive bool) *ESCLScanner {
+	return &ESCLScanner{baseURL: baseURL, client: NewRetryableHTTPClient(retryConfig), interactive: interactive}
 }
 
 // getScannerStatus queries the scanner status endpoint and returns the parsed status
 func (s *ESCLScanner) getScannerStatus() (*ScannerStatusResponse, error) {
 	url := fmt.Sprintf("%s/eSCL/ScannerStatus", s.baseURL)
 
-	resp, err := http.Get(url)
+	resp, err := s.client.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query scanner status: %w", err)
 	}
@@ -89,6 +100,45 @@ func getESCLMimeType(ext string) (string, error) {
 	}
 }
 
+// buildScanSettings renders the eSCL ScanSettings XML body for a scan job,
+// requesting documents in mimeType from the given inputSource
+// (Platen or Feeder), using the tool's historical hardcoded defaults for
+// everything else. It is kept for callers (Scan and batch scanning) that
+// don't need the full ScanOptions validation path.
+func buildScanSettings(mimeType, inputSource string) string {
+	opts := ScanOptions{Source: inputSource, Format: extensionForMimeType(mimeType)}.withDefaults()
+	return buildScanSettingsXML(opts, mimeType)
+}
+
+// buildScanSettingsXML renders the eSCL ScanSettings XML body for opts,
+// which must already be defaulted and validated.
+func buildScanSettingsXML(opts ScanOptions, mimeType string) string {
+	width, height := "2550", "3508"
+	if w, h, ok := strings.Cut(opts.Region, "x"); ok {
+		width, height = w, h
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<scan:ScanSettings xmlns:scan="http://schemas.hp.com/imaging/escl/2011/05/03" xmlns:pwg="http://www.pwg.org/schemas/2010/12/sm">
+  <pwg:Version>2.0</pwg:Version>
+  <scan:Intent>%s</scan:Intent>
+  <pwg:ScanRegions>
+    <pwg:ScanRegion>
+      <pwg:ContentRegionUnits>escl:ThreeHundredthsOfInches</pwg:ContentRegionUnits>
+      <pwg:XOffset>0</pwg:XOffset>
+      <pwg:YOffset>0</pwg:YOffset>
+      <pwg:Width>%s</pwg:Width>
+      <pwg:Height>%s</pwg:Height>
+    </pwg:ScanRegion>
+  </pwg:ScanRegions>
+  <scan:InputSource>%s</scan:InputSource>
+  <scan:ColorMode>%s</scan:ColorMode>
+  <scan:XResolution>%d</scan:XResolution>
+  <scan:YResolution>%d</scan:YResolution>
+  <pwg:DocumentFormat>%s</pwg:DocumentFormat>
+</scan:ScanSettings>`, opts.Intent, width, height, opts.Source, opts.ColorMode, opts.Resolution, opts.Resolution, mimeType)
+}
+
 // Scan performs a scan using the eSCL (AirScan) protocol and returns the path to the scanned file
 // format specifies the output format extension (e.g., ".pdf", ".png", ".jpg")
 func (s *ESCLScanner) Scan(format string) (string, error) {
@@ -116,25 +166,7 @@ func (s *ESCLScanner) Scan(format string) (string, error) {
 	log.Printf("Scanning via eSCL to: %s (format: %s)", outputFile, mimeType)
 
 	// Create scan job with specified format
-	scanSettings := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<scan:ScanSettings xmlns:scan="http://schemas.hp.com/imaging/escl/2011/05/03" xmlns:pwg="http://www.pwg.org/schemas/2010/12/sm">
-  <pwg:Version>2.0</pwg:Version>
-  <scan:Intent>Document</scan:Intent>
-  <pwg:ScanRegions>
-    <pwg:ScanRegion>
-      <pwg:ContentRegionUnits>escl:ThreeHundredthsOfInches</pwg:ContentRegionUnits>
-      <pwg:XOffset>0</pwg:XOffset>
-      <pwg:YOffset>0</pwg:YOffset>
-      <pwg:Width>2550</pwg:Width>
-      <pwg:Height>3508</pwg:Height>
-    </pwg:ScanRegion>
-  </pwg:ScanRegions>
-  <scan:InputSource>Platen</scan:InputSource>
-  <scan:ColorMode>RGB24</scan:ColorMode>
-  <scan:XResolution>300</scan:XResolution>
-  <scan:YResolution>300</scan:YResolution>
-  <pwg:DocumentFormat>%s</pwg:DocumentFormat>
-</scan:ScanSettings>`, mimeType)
+	scanSettings := buildScanSettings(mimeType, "Platen")
 
 	// Submit scan job
 	jobURL, err := s.createScanJob(scanSettings)
@@ -160,73 +192,127 @@ func (s *ESCLScanner) Scan(format string) (string, error) {
 	return outputFile, nil
 }
 
-func (s *ESCLScanner) createScanJob(settings string) (string, error) {
-	url := fmt.Sprintf("%s/eSCL/ScanJobs", s.baseURL)
-	maxRetries := 5
-	userPromptedFor503 := false
+// ScanWithOptions performs a scan like Scan, but with user-supplied options
+// validated (and, where necessary, downgraded with a logged warning)
+// against the device's advertised ScannerCapabilities.
+func (s *ESCLScanner) ScanWithOptions(opts ScanOptions) (string, error) {
+	opts = opts.withDefaults()
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("Retrying scan job creation (attempt %d/%d)...", attempt+1, maxRetries)
-			time.Sleep(2 * time.Second)
-		}
+	caps, err := s.GetCapabilities()
+	if err != nil {
+		log.Printf("Warning: failed to query scanner capabilities, using requested options as-is: %v", err)
+		caps = nil
+	}
+	opts = validateAgainstCapabilities(opts, caps)
 
-		req, err := http.NewRequest("POST", url, bytes.NewBufferString(settings))
-		if err != nil {
-			return "", err
-		}
+	mimeType, err := getESCLMimeType(opts.Format)
+	if err != nil {
+		return "", err
+	}
 
-		req.Header.Set("Content-Type", "text/xml")
+	tmpDir := filepath.Join(os.TempDir(), "paperless-scanner")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
 
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return "", err
-		}
+	timestamp := time.Now().Format("20060102-150405")
+	outputFile := filepath.Join(tmpDir, fmt.Sprintf("scan-%s%s", timestamp, opts.Format))
 
-		if resp.StatusCode == http.StatusCreated {
-			// Get the job location from the Location header
-			location := resp.Header.Get("Location")
-			resp.Body.Close()
-			if location == "" {
-				return "", fmt.Errorf("no Location header in response")
-			}
-			return location, nil
-		}
+	log.Printf("Scanning via eSCL to: %s (format: %s, resolution: %d, color: %s, source: %s)",
+		outputFile, mimeType, opts.Resolution, opts.ColorMode, opts.Source)
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
+	jobURL, err := s.createScanJob(buildScanSettingsXML(opts, mimeType))
+	if err != nil {
+		return "", fmt.Errorf("failed to create scan job: %w", err)
+	}
 
-		// Handle 503 Service Unavailable - likely an error on scanner screen
-		if resp.StatusCode == http.StatusServiceUnavailable && !userPromptedFor503 {
-			log.Printf("Scanner returned 503 Service Unavailable")
+	log.Printf("Scan job created: %s", jobURL)
+	time.Sleep(2 * time.Second)
 
-			// Query scanner status to check for errors
-			status, err := s.getScannerStatus()
-			if err != nil {
-				log.Printf("Warning: Failed to query scanner status: %v", err)
-			} else {
-				log.Printf("Scanner state: %s", status.State)
-			}
+	if err := s.downloadDocument(jobURL, outputFile); err != nil {
+		return "", fmt.Errorf("failed to download scan: %w", err)
+	}
 
-			// Prompt user to dismiss error regardless of status check result
-			// (503 typically means there's an error displayed)
-			if !s.promptUserToDismissError() {
-				return "", fmt.Errorf("scan aborted by user")
-			}
-			userPromptedFor503 = true
-			// Reset retry counter after user dismisses error
-			attempt = -1 // Will be 0 after continue
-			continue
+	if _, err := os.Stat(outputFile); os.IsNotExist(err) {
+		return "", fmt.Errorf("scan file was not created: %s", outputFile)
+	}
+
+	return outputFile, nil
+}
+
+// promptToDismissIfScannerBusy is the tool's single policy for the
+// interactive case an HTTP retry can't fix: a 503 that persists because the
+// scanner has an error on its screen rather than just being momentarily
+// busy. It checks scanner status for diagnostics and only prompts the user
+// to dismiss an error when the reported state isn't a normal transient one;
+// it returns false if the user chose to abort. When s.interactive is false
+// (e.g. the serve daemon, which has no attached terminal to prompt on) it
+// skips the prompt and treats a non-transient state as a hard failure.
+func (s *ESCLScanner) promptToDismissIfScannerBusy() bool {
+	status, err := s.getScannerStatus()
+	if err != nil {
+		log.Printf("Warning: Failed to query scanner status: %v", err)
+	} else {
+		log.Printf("Scanner state: %s", status.State)
+		if status.State == "Idle" || status.State == "Processing" {
+			return true
 		}
+	}
+
+	if !s.interactive {
+		log.Printf("Scanner requires manual attention and no interactive terminal is attached, giving up")
+		return false
+	}
+
+	return s.promptUserToDismissError()
+}
+
+// dismissScannerErrorAndRetry runs op; if it comes back with a persistent
+// 503 (the shared HTTP retry transport has already exhausted its own
+// retries), it applies promptToDismissIfScannerBusy and retries op exactly
+// once more on success.
+func (s *ESCLScanner) dismissScannerErrorAndRetry(op func() (*http.Response, error)) (*http.Response, error) {
+	resp, err := op()
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	log.Printf("Scanner returned 503 Service Unavailable after retrying")
+
+	if !s.promptToDismissIfScannerBusy() {
+		return nil, fmt.Errorf("scan aborted by user")
+	}
+
+	return op()
+}
+
+func (s *ESCLScanner) createScanJob(settings string) (string, error) {
+	url := fmt.Sprintf("%s/eSCL/ScanJobs", s.baseURL)
 
-		// For last attempt or non-503 errors, return the error
-		if attempt == maxRetries-1 || resp.StatusCode != http.StatusServiceUnavailable {
-			return "", fmt.Errorf("failed to create scan job, status: %d, body: %s", resp.StatusCode, string(body))
+	resp, err := s.dismissScannerErrorAndRetry(func() (*http.Response, error) {
+		req, err := http.NewRequest("POST", url, bytes.NewBufferString(settings))
+		if err != nil {
+			return nil, err
 		}
+		req.Header.Set("Content-Type", "text/xml")
+		return s.client.Do(req)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to create scan job, status: %d, body: %s", resp.StatusCode, string(body))
 	}
 
-	return "", fmt.Errorf("failed to create scan job after %d attempts", maxRetries)
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("no Location header in response")
+	}
+	return location, nil
 }
 
 func (s *ESCLScanner) downloadDocument(jobURL string, outputFile string) error {
@@ -241,20 +327,25 @@ func (s *ESCLScanner) downloadDocument(jobURL string, outputFile string) error {
 	// Build document URL
 	docURL := fmt.Sprintf("%s/eSCL/ScanJobs/%s/NextDocument", s.baseURL, jobID)
 
-	// Poll for document (it might not be ready immediately)
+	// Poll for document (it might not be ready immediately). Retriable HTTP
+	// failures are already handled by s.client's transport; this loop only
+	// deals with the eSCL-specific "not ready yet" (404) and "busy" (503)
+	// semantics of NextDocument.
 	maxRetries := 30
 	userPromptedFor503 := false
+	spinner := newPollSpinner("Scan")
 
 	for i := 0; i < maxRetries; i++ {
-		log.Printf("Attempting to download scan (attempt %d/%d)...", i+1, maxRetries)
+		spinner.Tick(i+1, maxRetries)
 
-		resp, err := http.Get(docURL)
+		resp, err := s.client.Get(docURL)
 		if err != nil {
 			return err
 		}
 
 		if resp.StatusCode == http.StatusOK {
 			defer resp.Body.Close()
+			spinner.Done()
 
 			// Save the document
 			outFile, err := os.Create(outputFile)
@@ -263,7 +354,7 @@ func (s *ESCLScanner) downloadDocument(jobURL string, outputFile string) error {
 			}
 			defer outFile.Close()
 
-			_, err = io.Copy(outFile, resp.Body)
+			_, err = io.Copy(outFile, newProgressReader(resp.Body, resp.ContentLength, "Downloading scan"))
 			if err != nil {
 				return err
 			}
@@ -281,34 +372,172 @@ func (s *ESCLScanner) downloadDocument(jobURL string, outputFile string) error {
 		}
 
 		if resp.StatusCode == http.StatusServiceUnavailable {
-			// 503 might indicate an error on the scanner screen
-			// Check scanner status to determine if we should prompt the user
 			if !userPromptedFor503 {
-				status, err := s.getScannerStatus()
-				if err != nil {
-					log.Printf("Warning: Failed to query scanner status: %v", err)
-				} else {
-					log.Printf("Scanner state: %s", status.State)
-					// If scanner is not in a normal state, it might have an error displayed
-					if status.State != "Idle" && status.State != "Processing" {
-						// Prompt user to dismiss error
-						if !s.promptUserToDismissError() {
-							return fmt.Errorf("scan aborted by user")
-						}
-						userPromptedFor503 = true
-						// Reset retry counter to give more attempts after user dismisses error
-						i = 0
-						continue
-					}
+				spinner.Done()
+				if !s.promptToDismissIfScannerBusy() {
+					return fmt.Errorf("scan aborted by user")
 				}
+				userPromptedFor503 = true
+				// Reset retry counter to give more attempts after user dismisses error
+				i = 0
+				continue
 			}
 			// Document not ready yet, wait and retry
 			time.Sleep(1 * time.Second)
 			continue
 		}
 
+		spinner.Done()
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
+	spinner.Done()
 	return fmt.Errorf("scan document not ready after %d attempts", maxRetries)
 }
+
+// ScanBatch scans every page waiting in the document feeder (ADF) in a
+// single job and assembles the pages into one multi-page PDF. It creates a
+// single ScanJob with InputSource=Feeder, then repeatedly fetches
+// NextDocument: each successful fetch is one page, and a 404/410 response
+// means the feeder has run out of paper.
+func (s *ESCLScanner) ScanBatch(opts BatchOptions) ([]string, error) {
+	format := opts.Format
+	if format == "" {
+		format = ".pdf"
+	}
+
+	// Pages are always pulled as JPEG; assemblePDF re-encodes and combines
+	// them into a single PDF, whatever extension format names.
+	scanSettings := buildScanSettings("image/jpeg", "Feeder")
+
+	jobURL, err := s.createScanJob(scanSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch scan job: %w", err)
+	}
+	log.Printf("Batch scan job created: %s", jobURL)
+
+	tmpDir := filepath.Join(os.TempDir(), "paperless-scanner")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	timestamp := time.Now().Format("20060102-150405")
+
+	var pagePaths []string
+	for page := 1; opts.MaxPages == 0 || page <= opts.MaxPages; page++ {
+		pageFile := filepath.Join(tmpDir, fmt.Sprintf("batch-%s-%03d.jpg", timestamp, page))
+
+		empty, err := s.downloadNextFeederPage(jobURL, pageFile, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download page %d: %w", page, err)
+		}
+		if empty {
+			log.Printf("Feeder is empty after %d page(s)", page-1)
+			break
+		}
+
+		log.Printf("Collected page %d from feeder", page)
+		pagePaths = append(pagePaths, pageFile)
+	}
+
+	if len(pagePaths) == 0 {
+		return nil, fmt.Errorf("no pages were scanned from the feeder")
+	}
+
+	outputFile := filepath.Join(tmpDir, fmt.Sprintf("scan-batch-%s%s", timestamp, format))
+	if err := assemblePDF(pagePaths, outputFile, opts.PreProcess); err != nil {
+		return nil, fmt.Errorf("failed to assemble batch PDF: %w", err)
+	}
+
+	return append([]string{outputFile}, pagePaths...), nil
+}
+
+// downloadNextFeederPage fetches a single page from a feeder-driven scan
+// job's NextDocument endpoint. It returns (true, nil) when the feeder has
+// run out of pages (404/410), and (false, nil) after successfully writing a
+// page to outputFile.
+func (s *ESCLScanner) downloadNextFeederPage(jobURL string, outputFile string, page int) (bool, error) {
+	re := regexp.MustCompile(`/ScanJobs/(.+)$`)
+	matches := re.FindStringSubmatch(jobURL)
+	if len(matches) < 2 {
+		return false, fmt.Errorf("invalid job URL: %s", jobURL)
+	}
+	jobID := matches[1]
+	docURL := fmt.Sprintf("%s/eSCL/ScanJobs/%s/NextDocument", s.baseURL, jobID)
+
+	const maxRetries = 30
+	userPromptedFor503 := false
+	spinner := newPollSpinner(fmt.Sprintf("Page %d", page))
+
+	for i := 0; i < maxRetries; i++ {
+		spinner.Tick(i+1, maxRetries)
+
+		resp, err := s.client.Get(docURL)
+		if err != nil {
+			return false, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			defer resp.Body.Close()
+			spinner.Done()
+			outFile, err := os.Create(outputFile)
+			if err != nil {
+				return false, err
+			}
+			defer outFile.Close()
+			label := fmt.Sprintf("Downloading page %d", page)
+			if _, err := io.Copy(outFile, newProgressReader(resp.Body, resp.ContentLength, label)); err != nil {
+				return false, err
+			}
+			return false, nil
+
+		case http.StatusNotFound, http.StatusGone:
+			// Feeder empty: no more documents to pull from this job.
+			resp.Body.Close()
+			spinner.Done()
+			return true, nil
+
+		case http.StatusServiceUnavailable:
+			resp.Body.Close()
+			if !userPromptedFor503 {
+				spinner.Done()
+				if !s.promptToDismissIfScannerBusy() {
+					return false, fmt.Errorf("scan aborted by user")
+				}
+				userPromptedFor503 = true
+				i = -1
+				continue
+			}
+			// Page not ready yet; wait and retry.
+			time.Sleep(1 * time.Second)
+			continue
+
+		default:
+			resp.Body.Close()
+			spinner.Done()
+			return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+	}
+
+	spinner.Done()
+	return false, fmt.Errorf("feeder page not ready after %d attempts", maxRetries)
+}
+
+// Capabilities reports the output formats this backend can produce, read
+// from the device's ScannerCapabilities when reachable.
+func (s *ESCLScanner) Capabilities() (BackendCapabilities, error) {
+	caps, err := s.GetCapabilities()
+	if err != nil || len(caps.DocumentFormats) == 0 {
+		return BackendCapabilities{Formats: []string{"application/pdf", "image/jpeg", "image/png"}}, nil
+	}
+	return BackendCapabilities{Formats: caps.DocumentFormats}, nil
+}
+
+// Status reports the scanner's current eSCL state (e.g. "Idle", "Processing").
+func (s *ESCLScanner) Status() (string, error) {
+	status, err := s.getScannerStatus()
+	if err != nil {
+		return "", err
+	}
+	return status.State, nil
+}
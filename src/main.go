@@ -6,13 +6,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	ScannerURL    string
-	PaperlessURL  string
+	ScannerURL     string
+	PaperlessURL   string
 	PaperlessToken string
 }
 
@@ -58,13 +59,42 @@ func loadConfig(requirePaperless bool, scannerURL, paperlessURL, paperlessToken
 }
 
 func main() {
+	// `serve` runs the tool as a long-lived daemon instead of performing a
+	// single scan; it has its own flag set since its flags don't overlap
+	// cleanly with the one-shot flags below.
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServeCommand(os.Args[2:])
+		return
+	}
+
 	// Define CLI flags
 	outputPath := flag.String("output", "", "Save scan to this path (required if not uploading to Paperless)")
 	uploadToPaperless := flag.Bool("upload-to-paperless", false, "Upload scan to Paperless-ngx")
+	batch := flag.Bool("batch", false, "Scan every page in the document feeder (ADF) into a single multi-page PDF")
+	batchMaxPages := flag.Int("batch-max-pages", 0, "Maximum number of pages to pull from the feeder (0 = no limit)")
+	batchPreprocess := flag.Bool("batch-preprocess", true, "Binarize and deskew each page before assembling the batch PDF")
+	resolution := flag.Int("resolution", 0, "Scan resolution in DPI (eSCL only; falls back to the closest supported value)")
+	colorMode := flag.String("color-mode", "", "Scan color mode: RGB24, Grayscale8, or BlackAndWhite1 (eSCL only)")
+	source := flag.String("source", "", "Input source: Platen, Feeder, or Duplex (eSCL only)")
+	intent := flag.String("intent", "", "Scan intent: Document, Photo, TextAndGraphic, or Preview (eSCL only)")
+	region := flag.String("region", "", "Scan region as \"<width>x<height>\" in 1/300 inch units (eSCL only)")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	quiet := flag.Bool("quiet", false, "Disable interactive progress bars/spinners, emitting periodic log lines instead")
+	discover := flag.Bool("discover", false, "Discover scanners on the LAN via mDNS/DNS-SD and exit")
+	scannerName := flag.String("scanner-name", "", "Resolve a friendly scanner name (from --discover) to a URL")
 	scannerURL := flag.String("scanner_url", "", "Scanner URL (overrides SCANNER_URL env var)")
 	paperlessURL := flag.String("paperless_url", "", "Paperless URL (overrides PAPERLESS_URL env var)")
 	paperlessToken := flag.String("paperless_token", "", "Paperless API token (overrides PAPERLESS_TOKEN env var)")
+	title := flag.String("title", "", "Document title to set in Paperless (defaults to Paperless' own auto-generated title)")
+	created := flag.String("created", "", "Document creation date/time to set in Paperless (ISO 8601, defaults to Paperless' own auto-detected date)")
+	tags := flag.String("tags", "", "Comma-separated tags to apply in Paperless, creating any that don't exist yet")
+	correspondent := flag.String("correspondent", "", "Correspondent to set in Paperless (must already exist)")
+	docType := flag.String("doctype", "", "Document type to set in Paperless (must already exist)")
+	asn := flag.String("asn", "", "Archive serial number to set in Paperless")
+	customFields := flag.String("custom-fields", "", "Comma-separated name=value pairs for custom fields to set in Paperless (must already exist)")
+	maxRetries := flag.Int("max-retries", DefaultRetryConfig.MaxRetries, "Maximum attempts for a scanner/Paperless request before giving up")
+	retryBackoff := flag.Duration("retry-backoff", DefaultRetryConfig.BaseBackoff, "Base backoff between retries (doubles each attempt, capped and jittered)")
+	requestTimeout := flag.Duration("request-timeout", DefaultRetryConfig.RequestTimeout, "Per-request timeout for scanner/Paperless HTTP calls")
 
 	// Customize usage message
 	flag.Usage = func() {
@@ -79,6 +109,16 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s --upload-to-paperless\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Scan, save, and upload\n")
 		fmt.Fprintf(os.Stderr, "  %s -output scan.pdf --upload-to-paperless\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Scan every page from the document feeder into one PDF\n")
+		fmt.Fprintf(os.Stderr, "  %s --batch -output book.pdf\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # List scanners found on the LAN\n")
+		fmt.Fprintf(os.Stderr, "  %s --discover\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Scan using a friendly name instead of SCANNER_URL\n")
+		fmt.Fprintf(os.Stderr, "  %s --scanner-name \"Kitchen Printer\" -output scan.pdf\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Scan a photo at 600 DPI from the feeder (eSCL only)\n")
+		fmt.Fprintf(os.Stderr, "  %s --resolution 600 --source Feeder --intent Photo -output photo.pdf\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Run as a daemon with a watched folder and a daily scheduled scan\n")
+		fmt.Fprintf(os.Stderr, "  %s serve --watch-dir ./dropbox --schedule \"0 9 * * *\"\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Environment variables (can be set in .env file):\n")
 		fmt.Fprintf(os.Stderr, "  SCANNER_URL         Scanner device URL (required)\n")
 		fmt.Fprintf(os.Stderr, "  PAPERLESS_URL       Paperless-ngx server URL\n")
@@ -97,12 +137,45 @@ func main() {
 	if *verbose {
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 	}
+	SetQuietProgress(*quiet)
+
+	retryConfig := RetryConfig{
+		MaxRetries:     *maxRetries,
+		BaseBackoff:    *retryBackoff,
+		RequestTimeout: *requestTimeout,
+	}
+
+	// --discover lists scanners found on the LAN and exits; it doesn't need
+	// SCANNER_URL or Paperless configuration.
+	if *discover {
+		devices, err := DiscoverWithCache()
+		if err != nil {
+			log.Fatalf("Discovery failed: %v", err)
+		}
+		if len(devices) == 0 {
+			fmt.Println("No scanners found.")
+			return
+		}
+		for _, d := range devices {
+			fmt.Printf("%s\t%s\t%s\t%s\n", d.Name, d.Model, d.URL, strings.Join(d.Formats, ","))
+		}
+		return
+	}
 
 	// Validate flags
 	if !*uploadToPaperless && *outputPath == "" {
 		log.Fatalf("Error: Must specify either --upload-to-paperless or -output (or both)")
 	}
 
+	// Resolve --scanner-name to a URL before falling back to SCANNER_URL.
+	if *scannerName != "" {
+		resolvedURL, err := ResolveScannerName(*scannerName)
+		if err != nil {
+			log.Fatalf("Failed to resolve scanner name: %v", err)
+		}
+		*scannerURL = resolvedURL
+	}
+
 	// Load configuration
 	config, err := loadConfig(*uploadToPaperless, *scannerURL, *paperlessURL, *paperlessToken)
 	if err != nil {
@@ -120,17 +193,37 @@ func main() {
 		}
 	}
 
-	// Perform scan using the appropriate scanner type
+	// Pick the appropriate scanner backend: eSCL for HTTP(S) URLs, SANE for
+	// everything else (device names).
+	scanner, err := NewBackend(config.ScannerURL, retryConfig, true)
+	if err != nil {
+		log.Fatalf("Failed to initialize scanner backend: %v", err)
+	}
+
+	// Perform the scan, either a single page or a full ADF batch.
 	var scanFile string
+	var batchPages []string
+	requestedOptions := *resolution != 0 || *colorMode != "" || *source != "" || *intent != "" || *region != ""
 
-	// Check if it's an HTTP/HTTPS URL (eSCL scanner) or a SANE device
-	if len(config.ScannerURL) >= 7 && (config.ScannerURL[:7] == "http://" || config.ScannerURL[:8] == "https://") {
-		// Use eSCL scanner for HTTP URLs
-		esclScanner := NewESCLScanner(config.ScannerURL)
-		scanFile, err = esclScanner.Scan(outputFormat)
+	if *batch {
+		batchPages, err = scanner.ScanBatch(BatchOptions{
+			Format:     outputFormat,
+			MaxPages:   *batchMaxPages,
+			PreProcess: *batchPreprocess,
+		})
+		if err == nil {
+			scanFile = batchPages[0]
+		}
+	} else if esclScanner, ok := scanner.(*ESCLScanner); ok && requestedOptions {
+		scanFile, err = esclScanner.ScanWithOptions(ScanOptions{
+			Resolution: *resolution,
+			ColorMode:  *colorMode,
+			Source:     *source,
+			Intent:     *intent,
+			Region:     *region,
+			Format:     outputFormat,
+		})
 	} else {
-		// Use SANE scanner for device names
-		scanner := NewScanner(config.ScannerURL)
 		scanFile, err = scanner.Scan(outputFormat)
 	}
 	if err != nil {
@@ -150,8 +243,30 @@ func main() {
 
 	// Upload to Paperless if --upload-to-paperless specified
 	if *uploadToPaperless {
-		client := NewPaperlessClient(config.PaperlessURL, config.PaperlessToken)
-		docID, err := client.UploadDocument(scanFile)
+		client := NewPaperlessClient(config.PaperlessURL, config.PaperlessToken, retryConfig)
+
+		uploadOpts := UploadOptions{
+			Title:               *title,
+			Created:             *created,
+			Correspondent:       *correspondent,
+			DocumentType:        *docType,
+			ArchiveSerialNumber: *asn,
+		}
+		if *tags != "" {
+			uploadOpts.Tags = strings.Split(*tags, ",")
+		}
+		if *customFields != "" {
+			uploadOpts.CustomFields = map[string]string{}
+			for _, pair := range strings.Split(*customFields, ",") {
+				name, value, ok := strings.Cut(pair, "=")
+				if !ok {
+					log.Fatalf("Invalid --custom-fields entry %q: expected name=value", pair)
+				}
+				uploadOpts.CustomFields[name] = value
+			}
+		}
+
+		docID, err := client.UploadDocumentWithOptions(scanFile, uploadOpts)
 		if err != nil {
 			log.Fatalf("Upload to Paperless failed: %v", err)
 		}
@@ -163,4 +278,10 @@ func main() {
 		os.Remove(scanFile)
 		log.Printf("Cleaned up temporary file: %s", scanFile)
 	}
+
+	// The raw per-page scans are only needed to build the assembled batch
+	// PDF; remove them once it has been saved/uploaded.
+	for i := 1; i < len(batchPages); i++ {
+		os.Remove(batchPages[i])
+	}
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -18,23 +18,29 @@ type PaperlessClient struct {
 	client  *http.Client
 }
 
-type PaperlessResponse struct {
-	ID int `json:"id"`
-}
-
-func NewPaperlessClient(baseURL, token string) *PaperlessClient {
+func NewPaperlessClient(baseURL, token string, retryConfig RetryConfig) *PaperlessClient {
 	// Remove trailing slash from baseURL
 	baseURL = strings.TrimRight(baseURL, "/")
 
 	return &PaperlessClient{
 		baseURL: baseURL,
 		token:   token,
-		client:  &http.Client{},
+		client:  NewRetryableHTTPClient(retryConfig),
 	}
 }
 
-// UploadDocument uploads a document to Paperless-ngx and returns the document ID
+// UploadDocument uploads a document to Paperless-ngx with no metadata beyond
+// the file itself, and returns the resulting document ID.
 func (p *PaperlessClient) UploadDocument(filePath string) (int, error) {
+	return p.UploadDocumentWithOptions(filePath, UploadOptions{})
+}
+
+// UploadDocumentWithOptions uploads a document to Paperless-ngx along with
+// the metadata in opts, resolving Correspondent/DocumentType/Tags from
+// names to IDs first. post_document returns a consuming-task UUID rather
+// than a document ID, so this polls PollTaskUntilDone to learn the final
+// document ID once Paperless-ngx has finished processing it.
+func (p *PaperlessClient) UploadDocumentWithOptions(filePath string, opts UploadOptions) (int, error) {
 	// Open the file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -42,28 +48,55 @@ func (p *PaperlessClient) UploadDocument(filePath string) (int, error) {
 	}
 	defer file.Close()
 
-	// Create multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// Add document file
-	part, err := writer.CreateFormFile("document", filepath.Base(filePath))
+	info, err := file.Stat()
 	if err != nil {
-		return 0, fmt.Errorf("failed to create form file: %w", err)
+		return 0, fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
-		return 0, fmt.Errorf("failed to copy file content: %w", err)
+	fields, err := p.resolveUploadFields(opts)
+	if err != nil {
+		return 0, err
 	}
 
-	// Close the writer to finalize the multipart message
-	if err := writer.Close(); err != nil {
-		return 0, fmt.Errorf("failed to close multipart writer: %w", err)
-	}
+	// Stream the multipart form directly into the request body through a
+	// pipe, rather than buffering it in memory first, so the progress
+	// reader below reflects bytes actually handed to the HTTP transport.
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		defer pw.Close()
+		defer writer.Close()
+
+		for name, value := range fields.simple {
+			if err := writer.WriteField(name, value); err != nil {
+				pw.CloseWithError(fmt.Errorf("failed to write %s field: %w", name, err))
+				return
+			}
+		}
+		// Paperless-ngx expects one "tags" form field per tag ID, not a
+		// single comma-separated value.
+		for _, tagID := range fields.tagIDs {
+			if err := writer.WriteField("tags", strconv.Itoa(tagID)); err != nil {
+				pw.CloseWithError(fmt.Errorf("failed to write tags field: %w", err))
+				return
+			}
+		}
+
+		part, err := writer.CreateFormFile("document", filepath.Base(filePath))
+		if err != nil {
+			pw.CloseWithError(fmt.Errorf("failed to create form file: %w", err))
+			return
+		}
+		if _, err := io.Copy(part, file); err != nil {
+			pw.CloseWithError(fmt.Errorf("failed to copy file content: %w", err))
+		}
+	}()
 
 	// Create the request
 	url := fmt.Sprintf("%s/api/documents/post_document/", p.baseURL)
-	req, err := http.NewRequest("POST", url, body)
+	label := fmt.Sprintf("Uploading %s", filepath.Base(filePath))
+	req, err := http.NewRequest("POST", url, newProgressReader(pr, info.Size(), label))
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -90,12 +123,11 @@ func (p *PaperlessClient) UploadDocument(filePath string) (int, error) {
 		return 0, fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	// Parse response to get document ID
-	var paperlessResp PaperlessResponse
-	if err := json.Unmarshal(respBody, &paperlessResp); err != nil {
-		// If we can't parse the response but upload succeeded, return 0 as ID
-		return 0, nil
+	// post_document returns the consuming task's UUID as a bare JSON string.
+	var taskID string
+	if err := json.Unmarshal(respBody, &taskID); err != nil {
+		return 0, fmt.Errorf("failed to parse upload response: %w", err)
 	}
 
-	return paperlessResp.ID, nil
+	return p.PollTaskUntilDone(taskID)
 }
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// UploadOptions describes Paperless metadata to attach to an uploaded
+// document beyond the file itself. Correspondent, DocumentType, and Tags are
+// names, not IDs; UploadDocumentWithOptions resolves them via the Resolve*/
+// EnsureTag helpers below. CustomFields is keyed by custom field name, also
+// resolved to an ID before upload.
+type UploadOptions struct {
+	Title               string
+	Created             string
+	Correspondent       string
+	DocumentType        string
+	Tags                []string
+	ArchiveSerialNumber string
+	CustomFields        map[string]string
+}
+
+// uploadFields holds the resolved multipart form fields for an upload:
+// plain string fields, plus tag IDs, which Paperless-ngx expects as one
+// repeated "tags" form field per tag rather than a single combined value.
+type uploadFields struct {
+	simple map[string]string
+	tagIDs []int
+}
+
+// resolveUploadFields turns opts' human-facing names into the IDs
+// Paperless-ngx's post_document endpoint expects, creating any tag that
+// doesn't exist yet.
+func (p *PaperlessClient) resolveUploadFields(opts UploadOptions) (uploadFields, error) {
+	fields := uploadFields{simple: map[string]string{}}
+
+	if opts.Title != "" {
+		fields.simple["title"] = opts.Title
+	}
+	if opts.Created != "" {
+		fields.simple["created"] = opts.Created
+	}
+	if opts.ArchiveSerialNumber != "" {
+		fields.simple["archive_serial_number"] = opts.ArchiveSerialNumber
+	}
+
+	if opts.Correspondent != "" {
+		id, err := p.ResolveCorrespondent(opts.Correspondent)
+		if err != nil {
+			return uploadFields{}, fmt.Errorf("failed to resolve correspondent: %w", err)
+		}
+		fields.simple["correspondent"] = strconv.Itoa(id)
+	}
+
+	if opts.DocumentType != "" {
+		id, err := p.resolveDocumentType(opts.DocumentType)
+		if err != nil {
+			return uploadFields{}, fmt.Errorf("failed to resolve document type: %w", err)
+		}
+		fields.simple["document_type"] = strconv.Itoa(id)
+	}
+
+	for _, tag := range opts.Tags {
+		id, err := p.EnsureTag(tag)
+		if err != nil {
+			return uploadFields{}, fmt.Errorf("failed to resolve tag %q: %w", tag, err)
+		}
+		fields.tagIDs = append(fields.tagIDs, id)
+	}
+
+	if len(opts.CustomFields) > 0 {
+		var values []customFieldValue
+		for name, value := range opts.CustomFields {
+			id, err := p.lookupIDByName("custom_fields", name)
+			if err != nil {
+				return uploadFields{}, fmt.Errorf("failed to resolve custom field %q: %w", name, err)
+			}
+			values = append(values, customFieldValue{Field: id, Value: value})
+		}
+
+		encoded, err := json.Marshal(values)
+		if err != nil {
+			return uploadFields{}, fmt.Errorf("failed to encode custom fields: %w", err)
+		}
+		fields.simple["custom_fields"] = string(encoded)
+	}
+
+	return fields, nil
+}
+
+// customFieldValue is the shape Paperless-ngx's post_document endpoint
+// expects for each entry of its "custom_fields" form field: the numeric
+// custom field ID (not its name) paired with the value to set.
+type customFieldValue struct {
+	Field int    `json:"field"`
+	Value string `json:"value"`
+}
+
+// paperlessTask is the subset of the /api/tasks/ response PollTaskUntilDone cares
+// about. Result carries a human-readable message on success (typically
+// "Success. New document id 123 created") or the failure reason.
+type paperlessTask struct {
+	TaskID string `json:"task_id"`
+	Status string `json:"status"`
+	Result string `json:"result"`
+}
+
+// documentIDFromResult extracts the document ID Paperless-ngx embeds in a
+// successful task's Result message.
+var documentIDFromResult = regexp.MustCompile(`(?i)document id (\d+)`)
+
+// PollTaskUntilDone polls /api/tasks/?task_id=<uuid> until Paperless-ngx
+// reports the consuming task as SUCCESS or FAILURE, returning the resulting
+// document ID. Modern Paperless-ngx returns a task UUID from
+// post_document rather than a document ID directly, so every upload has to
+// go through this step to learn what document was actually created.
+func (p *PaperlessClient) PollTaskUntilDone(taskID string) (int, error) {
+	spinner := newPollSpinner("Paperless")
+	const pollInterval = 2 * time.Second
+	const maxAttempts = 150 // 5 minutes at pollInterval
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		spinner.Tick(attempt, maxAttempts)
+
+		task, err := p.getTask(taskID)
+		if err != nil {
+			spinner.Done()
+			return 0, err
+		}
+
+		switch task.Status {
+		case "SUCCESS":
+			spinner.Done()
+			match := documentIDFromResult.FindStringSubmatch(task.Result)
+			if match == nil {
+				return 0, fmt.Errorf("task %s succeeded but document id could not be parsed from result: %q", taskID, task.Result)
+			}
+			docID, err := strconv.Atoi(match[1])
+			if err != nil {
+				return 0, fmt.Errorf("task %s succeeded but document id %q is not numeric: %w", taskID, match[1], err)
+			}
+			return docID, nil
+		case "FAILURE":
+			spinner.Done()
+			return 0, fmt.Errorf("task %s failed: %s", taskID, task.Result)
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	spinner.Done()
+	return 0, fmt.Errorf("task %s did not complete after %d attempts", taskID, maxAttempts)
+}
+
+// getTask fetches the single task matching taskID from /api/tasks/.
+func (p *PaperlessClient) getTask(taskID string) (*paperlessTask, error) {
+	reqURL := fmt.Sprintf("%s/api/tasks/?task_id=%s", p.baseURL, url.QueryEscape(taskID))
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build task status request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", p.token))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task status: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task status response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("task status returned non-OK status: %d", resp.StatusCode)
+	}
+
+	var tasks []paperlessTask
+	if err := json.Unmarshal(body, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse task status response: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no task found with id %s", taskID)
+	}
+
+	return &tasks[0], nil
+}
+
+// paperlessListResponse is the shape of Paperless-ngx's paginated list
+// endpoints, narrowed down to the id every resource exposes.
+type paperlessListResponse struct {
+	Results []struct {
+		ID int `json:"id"`
+	} `json:"results"`
+}
+
+// lookupIDByName queries a Paperless-ngx list endpoint (e.g.
+// "correspondents", "document_types", "tags") for an exact, case-insensitive
+// name match and returns its ID.
+func (p *PaperlessClient) lookupIDByName(resource, name string) (int, error) {
+	reqURL := fmt.Sprintf("%s/api/%s/?name__iexact=%s", p.baseURL, resource, url.QueryEscape(name))
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build %s lookup request: %w", resource, err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", p.token))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up %s %q: %w", resource, name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s lookup response: %w", resource, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s lookup returned non-OK status: %d", resource, resp.StatusCode)
+	}
+
+	var list paperlessListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return 0, fmt.Errorf("failed to parse %s lookup response: %w", resource, err)
+	}
+	if len(list.Results) == 0 {
+		return 0, fmt.Errorf("no %s named %q found", resource, name)
+	}
+
+	return list.Results[0].ID, nil
+}
+
+// createByName POSTs {"name": name} to a Paperless-ngx list endpoint and
+// returns the created resource's ID.
+func (p *PaperlessClient) createByName(resource, name string) (int, error) {
+	body, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal %s creation request: %w", resource, err)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/%s/", p.baseURL, resource), bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build %s creation request: %w", resource, err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", p.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s %q: %w", resource, name, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s creation response: %w", resource, err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("%s creation returned status %d: %s", resource, resp.StatusCode, string(respBody))
+	}
+
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return 0, fmt.Errorf("failed to parse %s creation response: %w", resource, err)
+	}
+
+	return created.ID, nil
+}
+
+// ResolveCorrespondent looks up an existing correspondent by name and
+// returns its ID.
+func (p *PaperlessClient) ResolveCorrespondent(name string) (int, error) {
+	return p.lookupIDByName("correspondents", name)
+}
+
+// resolveDocumentType looks up an existing document type by name and
+// returns its ID. Unlike tags, there's no CLI-facing way to create one on
+// the fly, so --doctype requires the type to already exist in Paperless.
+func (p *PaperlessClient) resolveDocumentType(name string) (int, error) {
+	return p.lookupIDByName("document_types", name)
+}
+
+// ResolveTag looks up an existing tag by name and returns its ID, failing if
+// no tag with that name exists.
+func (p *PaperlessClient) ResolveTag(name string) (int, error) {
+	return p.lookupIDByName("tags", name)
+}
+
+// EnsureTag looks up a tag by name, creating it if it doesn't already exist,
+// and returns its ID either way.
+func (p *PaperlessClient) EnsureTag(name string) (int, error) {
+	id, err := p.ResolveTag(name)
+	if err == nil {
+		return id, nil
+	}
+
+	log.Printf("Tag %q not found, creating it", name)
+	return p.createByName("tags", name)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// pointsPerInch and assumeDPI let us size each PDF page to match the
+// physical dimensions of a scan taken at a known resolution, rather than
+// guessing a fixed page size.
+const (
+	pointsPerInch = 72.0
+	assumeDPI     = 300.0
+)
+
+// assemblePDF decodes each page image in pagePaths (in order), optionally
+// runs it through preprocessPage, and writes a single multi-page PDF to
+// outputPath. Each page is embedded as a JPEG XObject (DCTDecode), which
+// keeps the writer small and dependency-free at the cost of re-encoding.
+func assemblePDF(pagePaths []string, outputPath string, preProcess bool) error {
+	if len(pagePaths) == 0 {
+		return fmt.Errorf("no pages to assemble")
+	}
+
+	type pageImage struct {
+		jpegBytes []byte
+		w, h      int
+	}
+
+	pages := make([]pageImage, 0, len(pagePaths))
+	for _, path := range pagePaths {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open page %s: %w", path, err)
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode page %s: %w", path, err)
+		}
+
+		if preProcess {
+			img = preprocessPage(img)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return fmt.Errorf("failed to encode page %s: %w", path, err)
+		}
+
+		bounds := img.Bounds()
+		pages = append(pages, pageImage{jpegBytes: buf.Bytes(), w: bounds.Dx(), h: bounds.Dy()})
+	}
+
+	// Object numbering: 1 = Catalog, 2 = Pages tree, then 3 image/content/page
+	// objects per scanned page. Bodies are built up front so each object's
+	// byte offset can be computed in a single pass over the final buffer.
+	const catalogObj = 1
+	const pagesObj = 2
+	bodies := make([]string, 2, 2+len(pages)*3)
+
+	pageObjNums := make([]int, 0, len(pages))
+	nextObj := 3
+	for _, p := range pages {
+		widthPt := float64(p.w) / assumeDPI * pointsPerInch
+		heightPt := float64(p.h) / assumeDPI * pointsPerInch
+
+		imgObj, contentObj, pageObj := nextObj, nextObj+1, nextObj+2
+		nextObj += 3
+
+		bodies = append(bodies, fmt.Sprintf(
+			"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream",
+			p.w, p.h, len(p.jpegBytes), p.jpegBytes))
+
+		content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", widthPt, heightPt)
+		bodies = append(bodies, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+
+		bodies = append(bodies, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, widthPt, heightPt, imgObj, contentObj))
+
+		pageObjNums = append(pageObjNums, pageObj)
+	}
+
+	kids := ""
+	for _, n := range pageObjNums {
+		kids += fmt.Sprintf("%d 0 R ", n)
+	}
+	bodies[catalogObj-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj)
+	bodies[pagesObj-1] = fmt.Sprintf("<< /Type /Pages /Kids [ %s] /Count %d >>", kids, len(pageObjNums))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(bodies)+1)
+	for i, body := range bodies {
+		offsets[i+1] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xrefStart := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n", len(bodies)+1)
+	out.WriteString("0000000000 65535 f \n")
+	for i := 1; i < len(offsets); i++ {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offsets[i])
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF", len(bodies)+1, catalogObj, xrefStart)
+
+	if err := os.WriteFile(outputPath, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write assembled PDF: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// sauvolaWindow and sauvolaK are the defaults recommended for scanned text
+// pages: a ~19px window and k=0.3 give a good balance between preserving
+// faint strokes and rejecting background shading.
+const (
+	sauvolaWindow = 19
+	sauvolaK      = 0.3
+	sauvolaR      = 128.0
+)
+
+// integralImages holds the running sum and running sum-of-squares of pixel
+// intensities, allowing the mean/stddev of any rectangular window to be
+// computed in O(1) regardless of window size.
+type integralImages struct {
+	sum   []float64
+	sumSq []float64
+	w, h  int
+}
+
+// newIntegralImages builds the integral image and integral-of-squares for
+// the grayscale image gray, each padded with a leading zero row/column so
+// that window sums can be computed without bounds checks.
+func newIntegralImages(gray *image.Gray) *integralImages {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	ii := &integralImages{
+		sum:   make([]float64, (w+1)*(h+1)),
+		sumSq: make([]float64, (w+1)*(h+1)),
+		w:     w + 1,
+		h:     h + 1,
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := float64(gray.GrayAt(x, y).Y)
+			above := ii.sum[y*ii.w+(x+1)]
+			left := ii.sum[(y+1)*ii.w+x]
+			aboveLeft := ii.sum[y*ii.w+x]
+			ii.sum[(y+1)*ii.w+(x+1)] = v + above + left - aboveLeft
+
+			aboveSq := ii.sumSq[y*ii.w+(x+1)]
+			leftSq := ii.sumSq[(y+1)*ii.w+x]
+			aboveLeftSq := ii.sumSq[y*ii.w+x]
+			ii.sumSq[(y+1)*ii.w+(x+1)] = v*v + aboveSq + leftSq - aboveLeftSq
+		}
+	}
+
+	return ii
+}
+
+// meanStdDev returns the mean and standard deviation of pixel intensities in
+// the window [x0,x1) x [y0,y1), where coordinates are clamped to the image.
+func (ii *integralImages) meanStdDev(x0, y0, x1, y1 int) (mean, stddev float64) {
+	if x0 < 0 {
+		x0 = 0
+	}
+	if y0 < 0 {
+		y0 = 0
+	}
+	if x1 > ii.w-1 {
+		x1 = ii.w - 1
+	}
+	if y1 > ii.h-1 {
+		y1 = ii.h - 1
+	}
+
+	n := float64((x1 - x0) * (y1 - y0))
+	if n <= 0 {
+		return 0, 0
+	}
+
+	sum := ii.sum[y1*ii.w+x1] - ii.sum[y0*ii.w+x1] - ii.sum[y1*ii.w+x0] + ii.sum[y0*ii.w+x0]
+	sumSq := ii.sumSq[y1*ii.w+x1] - ii.sumSq[y0*ii.w+x1] - ii.sumSq[y1*ii.w+x0] + ii.sumSq[y0*ii.w+x0]
+
+	mean = sum / n
+	variance := sumSq/n - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	return mean, math.Sqrt(variance)
+}
+
+// sauvolaBinarize converts img to a black-and-white image using Sauvola's
+// adaptive thresholding: for each pixel, a local threshold is derived from
+// the mean and standard deviation of its surrounding window, which copes
+// with the uneven lighting and shadows common on ADF/platen scans.
+func sauvolaBinarize(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+
+	ii := newIntegralImages(gray)
+	out := image.NewGray(bounds)
+	half := sauvolaWindow / 2
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			m, s := ii.meanStdDev(x-half, y-half, x+half+1, y+half+1)
+			threshold := m * (1 + sauvolaK*((s/sauvolaR)-1))
+
+			v := color.Gray{Y: 0}
+			if float64(gray.GrayAt(x, y).Y) > threshold {
+				v = color.Gray{Y: 255}
+			}
+			out.SetGray(x, y, v)
+		}
+	}
+
+	return out
+}
+
+// estimateSkewAngle estimates the page rotation, in degrees, by scoring a
+// small range of candidate angles against the variance of their horizontal
+// projection profile: text lines align into sharp peaks/troughs at the
+// correct angle, so the true skew maximizes that variance.
+func estimateSkewAngle(gray *image.Gray) float64 {
+	const maxAngle = 5.0
+	const step = 0.2
+
+	bestAngle := 0.0
+	bestScore := -1.0
+
+	for angle := -maxAngle; angle <= maxAngle; angle += step {
+		score := projectionVariance(gray, angle)
+		if score > bestScore {
+			bestScore = score
+			bestAngle = angle
+		}
+	}
+
+	// bestAngle is the sampling rotation that straightens the page (the same
+	// rotation rotateImage applies), so the page's own skew is its negative.
+	return -bestAngle
+}
+
+// projectionVariance rotates sample rows of gray by angle degrees (without
+// materializing a full rotated image) and returns the variance of the
+// resulting horizontal ink-density profile.
+func projectionVariance(gray *image.Gray, angle float64) float64 {
+	bounds := gray.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	cx, cy := float64(w)/2, float64(h)/2
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+
+	profile := make([]float64, h)
+	for y := 0; y < h; y++ {
+		var density float64
+		for x := 0; x < w; x += 2 {
+			sx := cos*(float64(x)-cx) - sin*(float64(y)-cy) + cx
+			sy := sin*(float64(x)-cx) + cos*(float64(y)-cy) + cy
+			if sx < 0 || sy < 0 || int(sx) >= w || int(sy) >= h {
+				continue
+			}
+			if gray.GrayAt(int(sx), int(sy)).Y < 128 {
+				density++
+			}
+		}
+		profile[y] = density
+	}
+
+	mean := 0.0
+	for _, v := range profile {
+		mean += v
+	}
+	mean /= float64(len(profile))
+
+	variance := 0.0
+	for _, v := range profile {
+		variance += (v - mean) * (v - mean)
+	}
+	return variance / float64(len(profile))
+}
+
+// deskew estimates and corrects the rotation of a scanned page, rotating the
+// image around its center by the negative of the detected skew angle.
+func deskew(img image.Image) image.Image {
+	gray := image.NewGray(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+
+	angle := estimateSkewAngle(gray)
+	if math.Abs(angle) < 0.1 {
+		return img
+	}
+
+	return rotateImage(img, -angle)
+}
+
+// rotateImage rotates img around its center by angle degrees, filling
+// uncovered corners with white.
+func rotateImage(img image.Image, angle float64) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	cx, cy := float64(w)/2, float64(h)/2
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+
+	out := image.NewRGBA(bounds)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := cos*(float64(x)-cx) - sin*(float64(y)-cy) + cx
+			sy := sin*(float64(x)-cx) + cos*(float64(y)-cy) + cy
+			if sx < 0 || sy < 0 || int(sx) >= w || int(sy) >= h {
+				out.Set(x, y, color.White)
+				continue
+			}
+			out.Set(x, y, img.At(bounds.Min.X+int(sx), bounds.Min.Y+int(sy)))
+		}
+	}
+
+	return out
+}
+
+// preprocessPage deskews and then binarizes a single scanned page,
+// returning a new cleaned-up image ready for PDF assembly; img itself is
+// not modified.
+func preprocessPage(img image.Image) image.Image {
+	straightened := deskew(img)
+	return sauvolaBinarize(straightened)
+}
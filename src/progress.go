@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// quietProgress disables interactive progress rendering (bars/spinners) even
+// when stderr is a TTY, falling back to periodic log lines. Set once from
+// main via SetQuietProgress.
+var quietProgress bool
+
+// SetQuietProgress configures whether progress reporting renders an
+// in-place bar/spinner (the default when stderr is a TTY) or periodic log
+// lines (when quiet is true, or whenever stderr isn't a TTY).
+func SetQuietProgress(quiet bool) {
+	quietProgress = quiet
+}
+
+// useInteractiveProgress reports whether progress should render as an
+// in-place bar/spinner rather than periodic log lines.
+func useInteractiveProgress() bool {
+	return !quietProgress && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// progressInterval is how often a transfer in progress re-renders: fast for
+// an in-place bar, slow for periodic log lines so they don't flood the log.
+func progressInterval() time.Duration {
+	if useInteractiveProgress() {
+		return 100 * time.Millisecond
+	}
+	return 2 * time.Second
+}
+
+// progressReader wraps an io.Reader, reporting bytes transferred toward an
+// expected total (0 if unknown) under label ("Downloading scan",
+// "Uploading invoice.pdf"). Wrap a response body or request body with it to
+// get progress for downloads and uploads for free.
+type progressReader struct {
+	r         io.Reader
+	label     string
+	total     int64
+	read      int64
+	start     time.Time
+	lastPrint time.Time
+}
+
+// newProgressReader wraps r so that reads toward a transfer of size total
+// bytes (0 if unknown) are reported under label.
+func newProgressReader(r io.Reader, total int64, label string) *progressReader {
+	return &progressReader{r: r, label: label, total: total, start: time.Now()}
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.read += int64(n)
+
+	if n > 0 {
+		p.report(false)
+	}
+	if err != nil {
+		p.report(true)
+	}
+	return n, err
+}
+
+// report renders the current progress, throttled by progressInterval except
+// on the final (done) call.
+func (p *progressReader) report(done bool) {
+	now := time.Now()
+	if !done && now.Sub(p.lastPrint) < progressInterval() {
+		return
+	}
+	p.lastPrint = now
+
+	speed := float64(p.read) / time.Since(p.start).Seconds()
+
+	if useInteractiveProgress() {
+		eta := ""
+		if p.total > 0 && speed > 0 && !done {
+			remaining := time.Duration(float64(p.total-p.read)/speed) * time.Second
+			eta = fmt.Sprintf(" ETA %s", remaining.Round(time.Second))
+		}
+		fmt.Fprintf(os.Stderr, "\r%s %s %s%s", p.label, renderBar(p.read, p.total), formatSpeed(speed), eta)
+		if done {
+			fmt.Fprintln(os.Stderr)
+		}
+		return
+	}
+
+	log.Printf("%s: %s (%s)", p.label, renderBar(p.read, p.total), formatSpeed(speed))
+}
+
+// pollSpinner reports progress through a polling loop that has no byte count
+// to show, e.g. waiting for a scan job's NextDocument to become ready. It
+// renders an animated spinner frame on a TTY, or a periodic log line
+// otherwise.
+type pollSpinner struct {
+	label     string
+	lastPrint time.Time
+	frame     int
+}
+
+// newPollSpinner creates a spinner reporting progress under label.
+func newPollSpinner(label string) *pollSpinner {
+	return &pollSpinner{label: label}
+}
+
+var spinnerFrames = []rune(`|/-\`)
+
+// Tick reports the current attempt (1-indexed) out of max (0 if open-ended),
+// throttled by progressInterval.
+func (p *pollSpinner) Tick(attempt, max int) {
+	if time.Since(p.lastPrint) < progressInterval() {
+		return
+	}
+	p.lastPrint = time.Now()
+
+	status := fmt.Sprintf("attempt %d", attempt)
+	if max > 0 {
+		status = fmt.Sprintf("attempt %d/%d", attempt, max)
+	}
+
+	if useInteractiveProgress() {
+		p.frame = (p.frame + 1) % len(spinnerFrames)
+		fmt.Fprintf(os.Stderr, "\r%c %s: waiting for scanner (%s)...", spinnerFrames[p.frame], p.label, status)
+		return
+	}
+	log.Printf("%s: waiting for scanner (%s)...", p.label, status)
+}
+
+// Done clears the spinner's line (no-op outside a TTY).
+func (p *pollSpinner) Done() {
+	if useInteractiveProgress() {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+// renderBar draws a fixed-width bracketed progress bar for done/total bytes.
+// If total is unknown (<= 0), it renders just the bytes transferred so far.
+func renderBar(done, total int64) string {
+	const width = 20
+	if total <= 0 {
+		return fmt.Sprintf("[%s]", formatBytes(done))
+	}
+	if done > total {
+		done = total
+	}
+	filled := int(float64(width) * float64(done) / float64(total))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	return fmt.Sprintf("[%s] %5.1f%%", bar, float64(done)/float64(total)*100)
+}
+
+// formatBytes renders n bytes using binary (KiB/MiB/...) units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatSpeed renders a transfer rate in bytes/sec using formatBytes' units.
+func formatSpeed(bytesPerSec float64) string {
+	return formatBytes(int64(bytesPerSec)) + "/s"
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryConfig controls the shared retry policy used by every outbound HTTP
+// client in the tool (eSCL and Paperless).
+type RetryConfig struct {
+	MaxRetries     int
+	BaseBackoff    time.Duration
+	RequestTimeout time.Duration
+}
+
+// DefaultRetryConfig mirrors the retry behavior the ad-hoc loops used to
+// hardcode: a handful of retries with a couple-second base backoff.
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries:     5,
+	BaseBackoff:    2 * time.Second,
+	RequestTimeout: 30 * time.Second,
+}
+
+// maxBackoff caps the computed exponential backoff so a flaky device can't
+// stall the tool for minutes between attempts.
+const maxBackoff = 30 * time.Second
+
+// RetriableError marks an error as safe to retry under the shared retry
+// policy, as opposed to a terminal error that should be surfaced
+// immediately. It lets non-HTTP call sites (business logic built on top of
+// retryTransport) opt into the same "retriable vs terminal" vocabulary.
+type RetriableError struct {
+	Err error
+}
+
+// NewRetriableError wraps err to mark it explicitly retriable.
+func NewRetriableError(err error) *RetriableError {
+	return &RetriableError{Err: err}
+}
+
+func (e *RetriableError) Error() string { return e.Err.Error() }
+func (e *RetriableError) Unwrap() error { return e.Err }
+
+// retryTransport wraps an http.RoundTripper, retrying requests that fail
+// with a retriable classification: network errors, 5xx, 408 (Request
+// Timeout), and 429 (Too Many Requests). Any other 4xx is treated as
+// terminal and returned immediately. Retry-After is honored when present.
+type retryTransport struct {
+	next   http.RoundTripper
+	config RetryConfig
+}
+
+// NewRetryableHTTPClient builds an *http.Client whose transport retries
+// retriable failures with exponential backoff and jitter, applying
+// config.RequestTimeout to each individual attempt rather than to the call
+// as a whole. The client itself carries no overall timeout: with MaxRetries
+// attempts and exponential backoff between them, a single deadline spanning
+// every attempt and every sleep would usually expire before the retries it's
+// supposed to bound ever ran.
+func NewRetryableHTTPClient(config RetryConfig) *http.Client {
+	return &http.Client{
+		Transport: &retryTransport{
+			next:   http.DefaultTransport,
+			config: config,
+		},
+	}
+}
+
+// isRetriableStatus classifies a response status code as retriable (network
+// blips and server-side overload) vs terminal (client errors other than
+// 408/429, which a retry can't fix).
+func isRetriableStatus(status int) bool {
+	if status >= 500 {
+		return true
+	}
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxRetries := t.config.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 1
+	}
+
+	// A request body can only be safely retried if it can be re-derived per
+	// attempt. req.GetBody is populated automatically for the small, fully
+	// in-memory bodies the tool builds itself (e.g. eSCL ScanSettings XML
+	// via bytes.NewBufferString). The large Paperless upload body is a pipe
+	// fed by a streaming multipart-encoding goroutine and has no GetBody;
+	// buffering it here ourselves to make it replayable would drain it at
+	// local pipe speed before the real network write ever starts, which
+	// defeats its progress reporting. So a body we can't re-derive gets
+	// exactly one attempt.
+	if req.Body != nil && req.GetBody == nil {
+		maxRetries = 1
+	}
+
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return nil, gerr
+			}
+			req.Body = body
+		}
+
+		resp, err = t.roundTripOnce(req)
+		if err == nil && !isRetriableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+
+		if attempt == maxRetries-1 {
+			break
+		}
+
+		wait := backoffWithJitter(t.config.BaseBackoff, attempt)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+			resp.Body.Close()
+		}
+
+		time.Sleep(wait)
+	}
+
+	return resp, err
+}
+
+// roundTripOnce performs a single attempt, bounding it to config.RequestTimeout.
+// The timeout is attached via context rather than http.Client.Timeout so it
+// applies per attempt instead of to the whole retry loop. The context can't
+// simply be canceled when RoundTrip returns, since that would tear down the
+// response body before the caller reads it; cancelOnCloseBody defers
+// cancellation until the body is closed.
+func (t *retryTransport) roundTripOnce(req *http.Request) (*http.Response, error) {
+	if t.config.RequestTimeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.config.RequestTimeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody releases a per-attempt context's resources once its
+// response body is closed, rather than as soon as RoundTrip returns.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// backoffWithJitter computes an exponential backoff for the given attempt
+// number (0-indexed), capped at maxBackoff, then adds up to a third of that
+// as jitter so that concurrent retries don't all land on the same instant.
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	backoff := base * time.Duration(1<<uint(attempt))
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff)/3 + 1))
+	return backoff + jitter
+}
+
+// parseRetryAfter parses a Retry-After header value expressed in seconds.
+// (The HTTP-date form is rare for scanner/DMS APIs and isn't supported.)
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
@@ -1,21 +1,87 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-type Scanner struct {
+// scanimageProgressRe matches the percentage scanimage reports on its own
+// progress output when invoked with --progress, e.g. "Progress: 45.03%".
+var scanimageProgressRe = regexp.MustCompile(`Progress:\s*([\d.]+)%`)
+
+// runScanimageWithProgress runs a scanimage invocation built with
+// --progress, mirroring its combined stdout/stderr into the returned string
+// (for the caller's own logging) while re-rendering scanimage's
+// self-reported percentage through our own progress reporting: an in-place
+// bar on a TTY, periodic log lines otherwise.
+func runScanimageWithProgress(cmd *exec.Cmd, label string) (string, error) {
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	var output bytes.Buffer
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		lastPrint := time.Time{}
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			output.WriteString(line)
+			output.WriteString("\n")
+
+			m := scanimageProgressRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			percent, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			if percent < 100 && time.Since(lastPrint) < progressInterval() {
+				continue
+			}
+			lastPrint = time.Now()
+
+			if useInteractiveProgress() {
+				fmt.Fprintf(os.Stderr, "\r%s %s", label, renderBar(int64(percent), 100))
+			} else {
+				log.Printf("%s: %.0f%%", label, percent)
+			}
+		}
+	}()
+
+	runErr := cmd.Run()
+	pw.Close()
+	<-done
+
+	if useInteractiveProgress() {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return output.String(), runErr
+}
+
+// SANEScanner drives a local or network scanner through the `scanimage`
+// command-line tool from SANE (Scanner Access Now Easy).
+type SANEScanner struct {
 	url string
 }
 
-func NewScanner(url string) *Scanner {
-	return &Scanner{url: url}
+func NewSANEScanner(url string) *SANEScanner {
+	return &SANEScanner{url: url}
 }
 
 // getScanImageFormat maps file extensions to scanimage format names
@@ -35,7 +101,7 @@ func getScanImageFormat(ext string) (string, error) {
 
 // Scan performs a scan using the SANE network scanner and returns the path to the scanned file
 // format specifies the output format extension (e.g., ".pdf", ".png", ".jpg")
-func (s *Scanner) Scan(format string) (string, error) {
+func (s *SANEScanner) Scan(format string) (string, error) {
 	// Default to PDF if no format specified
 	if format == "" {
 		format = ".pdf"
@@ -71,16 +137,17 @@ func (s *Scanner) Scan(format string) (string, error) {
 		"--output-file", outputFile,
 		"--progress",
 		"--resolution", "300", // 300 DPI for good quality
-		"--mode", "Color",     // Color scanning
+		"--mode", "Color", // Color scanning
 	)
 
-	// Capture output for logging
-	output, err := cmd.CombinedOutput()
+	// Mirror scanimage's own --progress percentage through our reporting
+	// while capturing its combined output for logging.
+	output, err := runScanimageWithProgress(cmd, "Scanning")
 	if err != nil {
-		return "", fmt.Errorf("scanimage command failed: %w\nOutput: %s", err, string(output))
+		return "", fmt.Errorf("scanimage command failed: %w\nOutput: %s", err, output)
 	}
 
-	log.Printf("Scanimage output: %s", string(output))
+	log.Printf("Scanimage output: %s", output)
 
 	// Verify the file was created
 	if _, err := os.Stat(outputFile); os.IsNotExist(err) {
@@ -89,3 +156,86 @@ func (s *Scanner) Scan(format string) (string, error) {
 
 	return outputFile, nil
 }
+
+// ScanBatch scans every page available in the document feeder using
+// `scanimage --batch` and assembles the resulting pages into a single
+// multi-page PDF.
+func (s *SANEScanner) ScanBatch(opts BatchOptions) ([]string, error) {
+	format := opts.Format
+	if format == "" {
+		format = ".pdf"
+	}
+
+	// Individual pages are always scanned as JPEG regardless of the final
+	// output format, since assemblePDF re-encodes each page itself.
+	tmpDir := filepath.Join(os.TempDir(), "paperless-scanner")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	timestamp := time.Now().Format("20060102-150405")
+	// Zero-padded so a lexical sort of the globbed pages (below) matches
+	// page order past 9 pages, same as the eSCL backend's ScanBatch.
+	batchPattern := filepath.Join(tmpDir, fmt.Sprintf("batch-%s-%%03d.jpg", timestamp))
+
+	log.Printf("Starting ADF batch scan to: %s", batchPattern)
+
+	batchCount := "0" // 0 means scan until the feeder reports out of paper
+	if opts.MaxPages > 0 {
+		batchCount = fmt.Sprintf("%d", opts.MaxPages)
+	}
+
+	args := []string{
+		"--device-name", s.url,
+		"--format", "jpeg",
+		"--batch=" + batchPattern,
+		"--batch-count", batchCount,
+		"--source", "ADF",
+		"--progress",
+		"--resolution", "300",
+		"--mode", "Color",
+	}
+
+	cmd := exec.Command("scanimage", args...)
+	output, err := runScanimageWithProgress(cmd, "Scanning batch")
+	if err != nil {
+		return nil, fmt.Errorf("scanimage batch command failed: %w\nOutput: %s", err, output)
+	}
+	log.Printf("Scanimage batch output: %s", output)
+
+	pagePaths, err := filepath.Glob(filepath.Join(tmpDir, fmt.Sprintf("batch-%s-*.jpg", timestamp)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scanned pages: %w", err)
+	}
+	if len(pagePaths) == 0 {
+		return nil, fmt.Errorf("no pages were scanned from the feeder")
+	}
+	sort.Strings(pagePaths)
+
+	outputFile := filepath.Join(tmpDir, fmt.Sprintf("scan-batch-%s%s", timestamp, format))
+	if err := assemblePDF(pagePaths, outputFile, opts.PreProcess); err != nil {
+		return nil, fmt.Errorf("failed to assemble batch PDF: %w", err)
+	}
+
+	return append([]string{outputFile}, pagePaths...), nil
+}
+
+// Capabilities reports the fixed set of output formats scanimage supports
+// for this backend.
+func (s *SANEScanner) Capabilities() (BackendCapabilities, error) {
+	return BackendCapabilities{Formats: []string{"pdf", "png", "jpeg"}}, nil
+}
+
+// Status reports whether the configured device is currently visible to
+// SANE by checking it against the `scanimage -L` device list.
+func (s *SANEScanner) Status() (string, error) {
+	output, err := exec.Command("scanimage", "-L").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to query SANE device list: %w", err)
+	}
+
+	if strings.Contains(string(output), s.url) {
+		return "Idle", nil
+	}
+	return "unavailable", nil
+}